@@ -0,0 +1,255 @@
+package localcopy
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Logger is the subset of *log.Logger that Client uses to report retries. Pass nil to
+// disable logging.
+type Logger interface {
+	Printf(format string, args ...interface{})
+}
+
+// RetryPolicy controls how a Client retries failed requests.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one. Values <= 1
+	// disable retries.
+	MaxAttempts int
+	// BaseBackoff is the delay before the first retry; it doubles on each subsequent retry.
+	BaseBackoff time.Duration
+	// Jitter is a random extra delay added to each backoff, up to this duration.
+	Jitter time.Duration
+}
+
+// DefaultRetryPolicy retries twice more after an initial failure, backing off exponentially.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseBackoff: 200 * time.Millisecond,
+	Jitter:      100 * time.Millisecond,
+}
+
+// HTTPError is returned when a request completes with a non-2xx status code.
+type HTTPError struct {
+	StatusCode int
+	URL        string
+	Body       []byte
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("unexpected status %d for %s", e.StatusCode, e.URL)
+}
+
+// Client groups the HTTP client, logger and retry policy used to load, download and check
+// remote resources. The zero value sends requests through http.DefaultClient with a single
+// attempt and no logging; NewClient returns one with a timeout and DefaultRetryPolicy.
+type Client struct {
+	HTTPClient *http.Client
+	Logger     Logger
+	Retry      RetryPolicy
+}
+
+// NewClient returns a Client with a 10s timeout and DefaultRetryPolicy.
+func NewClient() *Client {
+	return &Client{
+		HTTPClient: &http.Client{Timeout: time.Second * 10},
+		Retry:      DefaultRetryPolicy,
+	}
+}
+
+// DefaultClient is the Client used by the package-level functions.
+var DefaultClient = NewClient()
+
+// LoadRemote loads the resource from the given remote location.
+func (c *Client) LoadRemote(ctx context.Context, remoteURL string, read ReadFunc) (interface{}, error) {
+	response, err := c.do(ctx, http.MethodGet, remoteURL, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if err := checkStatus(response); err != nil {
+		return nil, err
+	}
+
+	in := bufio.NewReader(response.Body)
+	return read(in)
+}
+
+// Download downloads the resource from the given remote URL and stores it locally.
+func (c *Client) Download(ctx context.Context, remoteURL, localFilename string, read ReadFunc) error {
+	return c.DownloadWithOptions(ctx, remoteURL, localFilename, read, DownloadOptions{})
+}
+
+// DownloadWithOptions downloads the resource from the given remote URL and stores it
+// locally, optionally verifying it against a checksum. See the package-level
+// DownloadWithOptions for the atomicity guarantees.
+func (c *Client) DownloadWithOptions(ctx context.Context, remoteURL, localFilename string, read ReadFunc, options DownloadOptions) error {
+	response, err := c.do(ctx, http.MethodGet, remoteURL, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if err := checkStatus(response); err != nil {
+		return err
+	}
+
+	if err := downloadAtomic(response.Body, localFilename, options); err != nil {
+		return err
+	}
+	updateFreshnessMeta(localFilename, response.Header)
+	return nil
+}
+
+// NeedsUpdate checks whether the local copy needs to be updated from the given remote URL.
+// See the package-level NeedsUpdate for the freshness sidecar short-circuit.
+func (c *Client) NeedsUpdate(ctx context.Context, remoteURL, localFilename string) (bool, error) {
+	if _, statErr := os.Stat(localFilename); statErr == nil {
+		if meta, err := loadFreshnessMeta(localFilename); err == nil && isFresh(meta) {
+			return false, nil
+		}
+	}
+
+	response, err := c.do(ctx, http.MethodHead, remoteURL, nil, nil)
+	if err != nil {
+		return false, err
+	}
+	defer response.Body.Close()
+
+	if err := checkStatus(response); err != nil {
+		return false, err
+	}
+
+	var lastModified time.Time
+	if lastModifiedHeader, ok := response.Header[httpLastModified]; ok {
+		if len(lastModifiedHeader) == 0 {
+			return false, fmt.Errorf("Last-Modified header is empty")
+		}
+
+		lastModified, err = time.Parse(httpTimeFormat, lastModifiedHeader[0])
+		if err != nil {
+			return false, fmt.Errorf("cannot parse Last-Modified header: %v", err)
+		}
+	} else {
+		return false, fmt.Errorf("response does not contain a Last-Modified header")
+	}
+
+	localFileInfo, err := os.Stat(localFilename)
+	if os.IsNotExist(err) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return lastModified.After(localFileInfo.ModTime()), nil
+}
+
+// Update updates the local copy from the given remote URL, but only if an update is needed.
+func (c *Client) Update(ctx context.Context, remoteURL, localFilename string, read ReadFunc) (bool, error) {
+	needsUpdate, err := c.NeedsUpdate(ctx, remoteURL, localFilename)
+	if err != nil {
+		return false, err
+	}
+
+	if !needsUpdate {
+		return false, nil
+	}
+	return true, c.Download(ctx, remoteURL, localFilename, read)
+}
+
+// do performs a single logical request, retrying on 5xx responses and timeout net.Errors
+// according to c.Retry. configure, if non-nil, is called on the request before it is sent
+// on every attempt, so callers can set conditional/range headers that depend on local state.
+func (c *Client) do(ctx context.Context, method, url string, body io.Reader, configure func(*http.Request)) (*http.Response, error) {
+	policy := c.Retry
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < policy.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			if err := c.wait(ctx, policy, attempt); err != nil {
+				return nil, err
+			}
+			c.logf("retrying %s %s (attempt %d/%d) after error: %v", method, url, attempt+1, policy.MaxAttempts, lastErr)
+		}
+
+		request, err := http.NewRequestWithContext(ctx, method, url, body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build request: %v", err)
+		}
+		if configure != nil {
+			configure(request)
+		}
+
+		response, err := c.httpClient().Do(request)
+		if err != nil {
+			lastErr = err
+			if attempt+1 < policy.MaxAttempts && isRetryable(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		if response.StatusCode >= 500 && attempt+1 < policy.MaxAttempts {
+			response.Body.Close()
+			lastErr = fmt.Errorf("server returned %s", response.Status)
+			continue
+		}
+
+		return response, nil
+	}
+	return nil, lastErr
+}
+
+func (c *Client) wait(ctx context.Context, policy RetryPolicy, attempt int) error {
+	backoff := policy.BaseBackoff << uint(attempt-1)
+	if policy.Jitter > 0 {
+		backoff += time.Duration(rand.Int63n(int64(policy.Jitter)))
+	}
+	select {
+	case <-time.After(backoff):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func (c *Client) logf(format string, args ...interface{}) {
+	if c.Logger != nil {
+		c.Logger.Printf(format, args...)
+	}
+}
+
+func isRetryable(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return netErr.Timeout()
+	}
+	return false
+}
+
+func checkStatus(response *http.Response) error {
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 64*1024))
+	return &HTTPError{StatusCode: response.StatusCode, URL: response.Request.URL.String(), Body: body}
+}
@@ -0,0 +1,62 @@
+package localcopy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClientRetriesOn5xx(t *testing.T) {
+	attempts := 0
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer testServer.Close()
+
+	client := NewClient()
+	client.Retry = RetryPolicy{MaxAttempts: 3, BaseBackoff: time.Millisecond, Jitter: time.Millisecond}
+
+	value, err := client.LoadRemote(context.Background(), testServer.URL, readTestdata)
+	if err != nil {
+		t.Errorf("expected the request to eventually succeed, got: %v", err)
+		t.FailNow()
+	}
+	if value.(string) != "ok" {
+		t.Errorf("expected body %q, got %q", "ok", value)
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestClientReturnsHTTPError(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+	}))
+	defer testServer.Close()
+
+	client := NewClient()
+	client.Retry = RetryPolicy{MaxAttempts: 1}
+
+	_, err := client.LoadRemote(context.Background(), testServer.URL, readTestdata)
+	if err == nil {
+		t.Errorf("expected an error for a 404 response")
+		t.FailNow()
+	}
+	httpErr, ok := err.(*HTTPError)
+	if !ok {
+		t.Errorf("expected a *HTTPError, got %T: %v", err, err)
+		t.FailNow()
+	}
+	if httpErr.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, httpErr.StatusCode)
+	}
+}
@@ -0,0 +1,134 @@
+package localcopy
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// freshnessMeta is the per-file sidecar ("<file>.freshness.json") that records the
+// Cache-Control/Expires directives observed on the last successful Download, so that
+// NeedsUpdate can judge staleness locally without making any HTTP request.
+type freshnessMeta struct {
+	FetchedAt      time.Time `json:"fetchedAt"`
+	MaxAge         int       `json:"maxAgeSeconds,omitempty"`
+	HasMaxAge      bool      `json:"hasMaxAge,omitempty"`
+	Expires        time.Time `json:"expires,omitempty"`
+	NoCache        bool      `json:"noCache,omitempty"`
+	MustRevalidate bool      `json:"mustRevalidate,omitempty"`
+}
+
+func freshnessFilename(localFilename string) string {
+	return localFilename + ".freshness.json"
+}
+
+func loadFreshnessMeta(localFilename string) (freshnessMeta, error) {
+	var meta freshnessMeta
+	data, err := os.ReadFile(freshnessFilename(localFilename))
+	if err != nil {
+		return meta, err
+	}
+	err = json.Unmarshal(data, &meta)
+	return meta, err
+}
+
+func saveFreshnessMeta(localFilename string, meta freshnessMeta) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(freshnessFilename(localFilename), data, 0644)
+}
+
+// isFresh reports whether meta still guarantees the local copy is up to date, without
+// making any network request. no-cache and must-revalidate always force revalidation,
+// even within an otherwise unexpired max-age or Expires window.
+func isFresh(meta freshnessMeta) bool {
+	if meta.NoCache || meta.MustRevalidate {
+		return false
+	}
+	now := time.Now()
+	if meta.HasMaxAge && now.Before(meta.FetchedAt.Add(time.Duration(meta.MaxAge)*time.Second)) {
+		return true
+	}
+	if !meta.Expires.IsZero() && now.Before(meta.Expires) {
+		return true
+	}
+	return false
+}
+
+// cacheControlDirectives are the subset of RFC 7234 Cache-Control directives this package
+// understands. s-maxage, where present, takes precedence over max-age.
+type cacheControlDirectives struct {
+	MaxAge         int
+	HasMaxAge      bool
+	NoCache        bool
+	NoStore        bool
+	MustRevalidate bool
+}
+
+func parseCacheControl(header string) cacheControlDirectives {
+	var directives cacheControlDirectives
+	var maxAge, sMaxAge int
+	var hasMaxAge, hasSMaxAge bool
+
+	for _, part := range strings.Split(header, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		switch {
+		case part == "no-cache":
+			directives.NoCache = true
+		case part == "no-store":
+			directives.NoStore = true
+		case part == "must-revalidate":
+			directives.MustRevalidate = true
+		case strings.HasPrefix(part, "max-age="):
+			if value, err := strconv.Atoi(strings.TrimPrefix(part, "max-age=")); err == nil {
+				maxAge, hasMaxAge = value, true
+			}
+		case strings.HasPrefix(part, "s-maxage="):
+			if value, err := strconv.Atoi(strings.TrimPrefix(part, "s-maxage=")); err == nil {
+				sMaxAge, hasSMaxAge = value, true
+			}
+		}
+	}
+
+	if hasSMaxAge {
+		directives.MaxAge, directives.HasMaxAge = sMaxAge, true
+	} else if hasMaxAge {
+		directives.MaxAge, directives.HasMaxAge = maxAge, true
+	}
+	return directives
+}
+
+// updateFreshnessMeta records the freshness sidecar for localFilename from the response
+// headers of a successful download. A Cache-Control: no-store response refuses to persist
+// anything and removes any sidecar left over from a previous, more permissive response.
+func updateFreshnessMeta(localFilename string, header http.Header) error {
+	directives := parseCacheControl(header.Get("Cache-Control"))
+	if directives.NoStore {
+		os.Remove(freshnessFilename(localFilename))
+		return nil
+	}
+
+	meta := freshnessMeta{
+		FetchedAt:      time.Now(),
+		MaxAge:         directives.MaxAge,
+		HasMaxAge:      directives.HasMaxAge,
+		NoCache:        directives.NoCache,
+		MustRevalidate: directives.MustRevalidate,
+	}
+	if expiresHeader := header.Get("Expires"); expiresHeader != "" {
+		if expires, err := http.ParseTime(expiresHeader); err == nil {
+			meta.Expires = expires
+		}
+	}
+
+	if !meta.HasMaxAge && meta.Expires.IsZero() && !meta.NoCache && !meta.MustRevalidate {
+		os.Remove(freshnessFilename(localFilename))
+		return nil
+	}
+	return saveFreshnessMeta(localFilename, meta)
+}
@@ -0,0 +1,61 @@
+package localcopy
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestNeedsUpdateHonoursCacheControl(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "localcopy.TestNeedsUpdateHonoursCacheControl")
+	if err != nil {
+		t.Errorf("failed to create temp dir: %v", err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(tempDir)
+	localFilename := tempDir + "/copy.txt"
+
+	headRequests := 0
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			headRequests++
+		}
+		w.Header().Set("Cache-Control", "max-age=3600")
+		http.ServeFile(w, r, testFilename)
+	}))
+	defer testServer.Close()
+
+	if err := Download(testServer.URL, localFilename, readTestdata); err != nil {
+		t.Errorf("failed to download: %v", err)
+		t.FailNow()
+	}
+
+	needsUpdate, err := NeedsUpdate(testServer.URL, localFilename)
+	if err != nil {
+		t.Errorf("failed to check for update: %v", err)
+		t.FailNow()
+	}
+	if needsUpdate {
+		t.Errorf("expected the file to be considered fresh within its max-age window")
+	}
+	if headRequests != 0 {
+		t.Errorf("expected no HEAD request while the freshness window is still valid, got %d", headRequests)
+	}
+}
+
+func TestParseCacheControl(t *testing.T) {
+	directives := parseCacheControl("public, Max-Age=60, must-revalidate")
+	if !directives.HasMaxAge || directives.MaxAge != 60 {
+		t.Errorf("expected max-age 60, got %+v", directives)
+	}
+	if !directives.MustRevalidate {
+		t.Errorf("expected must-revalidate to be set")
+	}
+
+	directives = parseCacheControl("max-age=60, s-maxage=120")
+	if directives.MaxAge != 120 {
+		t.Errorf("expected s-maxage to take precedence, got %+v", directives)
+	}
+}
@@ -1,27 +1,65 @@
 /*
 Package localcopy allows to manage a local copy of a resource that is available through HTTP(s).
 
-It provides functions do download the resource and to check if an update of the local copy
-is necessary. The update check is done using a HEAD request and comparing the last
-modification date of the local copy with the Last-Modified header of the HTTP response.
+It provides functions to download the resource and to check if an update of the local copy
+is necessary, with several strategies layered on top of a plain Download:
+
+  - NeedsUpdate/Update check via a HEAD request, comparing the local copy's modification
+    time against the Last-Modified header, short-circuited by a Cache-Control/Expires
+    freshness sidecar recorded by a previous Download.
+  - ConditionalDownload issues a single GET carrying If-Modified-Since/If-None-Match so an
+    unchanged resource costs a 304 instead of a full transfer.
+  - ResumeDownload resumes an interrupted transfer from a ".part" file using Range/If-Range,
+    falling back to the same conditional check as ConditionalDownload when no transfer is
+    in progress.
+  - Mirror runs ConditionalDownload or ResumeDownload concurrently across many URLs, with Verify and Prune to
+    check and clean up a local tree against a manifest.
+
+Downloads are written atomically (a temporary file renamed into place) and can optionally be
+verified against a Checksum. All of the above have a DefaultClient they use for requests;
+construct a *Client directly to customize the underlying http.Client, retry policy or logger,
+including threading a context.Context through for cancellation.
 */
 package localcopy
 
 import (
 	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"hash"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
 const httpTimeFormat = time.RFC1123
 const httpLastModified = "Last-Modified"
+const httpETag = "ETag"
+const httpIfModifiedSince = "If-Modified-Since"
+const httpIfNoneMatch = "If-None-Match"
+
+// ChecksumAlgo identifies a supported digest algorithm for verifying downloads.
+type ChecksumAlgo string
 
-var httpClient = &http.Client{
-	Timeout: time.Second * 10,
+// SHA256 is currently the only supported ChecksumAlgo.
+const SHA256 ChecksumAlgo = "sha256"
+
+// Checksum is the expected digest of a downloaded resource.
+type Checksum struct {
+	Algo ChecksumAlgo
+	Hex  string
+}
+
+// DownloadOptions customizes the behaviour of DownloadWithOptions.
+type DownloadOptions struct {
+	// Expected, if set, is verified against the downloaded content before it is made
+	// visible at localFilename. A mismatch leaves the previous local copy untouched.
+	Expected *Checksum
 }
 
 // ReadFunc reads a value using the given reader.
@@ -43,84 +81,182 @@ func LoadLocal(localFilename string, read ReadFunc) (interface{}, error) {
 	return value, nil
 }
 
-// LoadRemote loads the resource from the given remote location.
+// LoadRemote loads the resource from the given remote location using DefaultClient.
 func LoadRemote(remoteURL string, read ReadFunc) (interface{}, error) {
-	resp, err := httpClient.Get(remoteURL)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	in := bufio.NewReader(resp.Body)
-	value, err := read(in)
-	if err != nil {
-		return nil, err
-	}
-	return value, nil
+	return DefaultClient.LoadRemote(context.Background(), remoteURL, read)
 }
 
-// Download downloads the resource from the given remote URL and stores it locally.
+// Download downloads the resource from the given remote URL and stores it locally,
+// using DefaultClient.
 func Download(remoteURL, localFilename string, read ReadFunc) error {
-	response, err := httpClient.Get(remoteURL)
-	if err != nil {
-		return fmt.Errorf("failed to download resource: %v", err)
-	}
-	defer response.Body.Close()
+	return DefaultClient.Download(context.Background(), remoteURL, localFilename, read)
+}
+
+// DownloadWithOptions downloads the resource from the given remote URL and stores it locally,
+// optionally verifying it against a checksum, using DefaultClient. The response is first
+// written to a temporary file next to localFilename and only renamed into place once it has
+// been fully received (and, if requested, its checksum verified), so an interrupted transfer
+// or a failed verification never leaves a partial or corrupt file at localFilename.
+func DownloadWithOptions(remoteURL, localFilename string, read ReadFunc, options DownloadOptions) error {
+	return DefaultClient.DownloadWithOptions(context.Background(), remoteURL, localFilename, read, options)
+}
 
+// downloadAtomic streams body into a temporary file next to localFilename, optionally
+// verifying a checksum, and only renames it into place once it has been fully written.
+// On any error the temporary file is removed and localFilename is left untouched.
+func downloadAtomic(body io.Reader, localFilename string, options DownloadOptions) (err error) {
 	os.MkdirAll(filepath.Dir(localFilename), os.ModePerm)
-	localFile, err := os.Create(localFilename)
+	tempFile, err := os.CreateTemp(filepath.Dir(localFilename), filepath.Base(localFilename)+".*.tmp")
 	if err != nil {
-		return fmt.Errorf("failed to open local file: %v", err)
+		return fmt.Errorf("failed to create temp file: %v", err)
 	}
-	defer localFile.Close()
+	tempName := tempFile.Name()
+	defer func() {
+		tempFile.Close()
+		if err != nil {
+			os.Remove(tempName)
+		}
+	}()
 
-	_, err = io.Copy(localFile, response.Body)
-	if err != nil {
+	reader := body
+	var hasher hash.Hash
+	if options.Expected != nil {
+		hasher, err = newHash(options.Expected.Algo)
+		if err != nil {
+			return err
+		}
+		reader = io.TeeReader(body, hasher)
+	}
+
+	if _, err = io.Copy(tempFile, reader); err != nil {
 		return fmt.Errorf("failed to store resource locally: %v", err)
 	}
 
+	if hasher != nil {
+		if digest := hex.EncodeToString(hasher.Sum(nil)); !strings.EqualFold(digest, options.Expected.Hex) {
+			return fmt.Errorf("checksum mismatch: expected %s, got %s", options.Expected.Hex, digest)
+		}
+	}
+
+	if err = tempFile.Sync(); err != nil {
+		return fmt.Errorf("failed to sync local file: %v", err)
+	}
+	if err = tempFile.Close(); err != nil {
+		return fmt.Errorf("failed to close local file: %v", err)
+	}
+
+	if err = os.Rename(tempName, localFilename); err != nil {
+		return fmt.Errorf("failed to finalize local file: %v", err)
+	}
 	return nil
 }
 
-// NeedsUpdate checks whether the local copy needs to be updated from the given remote URL.
-func NeedsUpdate(remoteURL, localFilename string) (bool, error) {
-	response, err := httpClient.Head(remoteURL)
+func newHash(algo ChecksumAlgo) (hash.Hash, error) {
+	switch algo {
+	case SHA256, "":
+		return sha256.New(), nil
+	default:
+		return nil, fmt.Errorf("unsupported checksum algorithm: %q", algo)
+	}
+}
+
+// ConditionalDownload downloads the resource from the given remote URL and stores it locally,
+// but only if the resource has changed, using DefaultClient. See (*Client).ConditionalDownload
+// for details.
+func ConditionalDownload(remoteURL, localFilename string, read ReadFunc) (bool, error) {
+	return DefaultClient.ConditionalDownload(context.Background(), remoteURL, localFilename, DownloadOptions{})
+}
+
+// ConditionalDownload downloads the resource from the given remote URL and stores it locally,
+// but only if the resource has changed. If localFilename already exists it issues a single
+// GET request carrying If-Modified-Since (derived from the local file's modification time)
+// and, if a sidecar ".etag" file also exists, If-None-Match; a 304 response then leaves the
+// local file untouched and returns (false, nil). If localFilename does not exist, no
+// validators are sent (a stale ".etag" sidecar left behind by a deleted file must never
+// cause a missing file to be reported as up to date), so the resource is always downloaded.
+// On a successful download the response's ETag header is persisted to the sidecar and the
+// local file's mtime is set from Last-Modified, so that later calls send accurate
+// validators. options.Expected, if set, is verified the same way as in DownloadWithOptions.
+func (c *Client) ConditionalDownload(ctx context.Context, remoteURL, localFilename string, options DownloadOptions) (bool, error) {
+	response, err := c.do(ctx, http.MethodGet, remoteURL, nil, func(request *http.Request) {
+		setValidators(request, localFilename)
+	})
 	if err != nil {
 		return false, err
 	}
-	var lastModified time.Time
-	if lastModifiedHeader, ok := response.Header[httpLastModified]; ok {
-		if len(lastModifiedHeader) == 0 {
-			return false, fmt.Errorf("Last-Modified header is empty")
-		}
+	defer response.Body.Close()
 
-		lastModified, err = time.Parse(httpTimeFormat, lastModifiedHeader[0])
-		if err != nil {
-			return false, fmt.Errorf("cannot parse Last-Modified header: %v", err)
-		}
-	} else {
-		return false, fmt.Errorf("response does not contain a Last-Modified header")
+	if response.StatusCode == http.StatusNotModified {
+		return false, nil
+	}
+	if err := checkStatus(response); err != nil {
+		return false, err
 	}
 
-	localFileInfo, err := os.Stat(localFilename)
-	if os.IsNotExist(err) {
-		return true, nil
-	} else if err != nil {
+	if err := downloadAtomic(response.Body, localFilename, options); err != nil {
 		return false, err
 	}
 
-	return lastModified.After(localFileInfo.ModTime()), nil
+	persistValidators(localFilename, response.Header)
+	return true, nil
 }
 
-// Update updates the local copy from the given remote URL, but only if an update is needed.
-func Update(remoteURL, localFilename string, read ReadFunc) (bool, error) {
-	needsUpdate, err := NeedsUpdate(remoteURL, localFilename)
-	if err != nil {
-		return false, err
+// setValidators sets If-Modified-Since/If-None-Match on request from the Last-Modified
+// mtime and ".etag" sidecar recorded for localFilename by a previous persistValidators
+// call, but only when localFilename itself still exists. A validator sidecar can outlive
+// the file it describes (e.g. an operator deleting a corrupt local copy); sending it
+// regardless would let the server legitimately 304 and leave the file missing forever.
+func setValidators(request *http.Request, localFilename string) {
+	localFileInfo, statErr := os.Stat(localFilename)
+	if statErr != nil {
+		return
 	}
+	request.Header.Set(httpIfModifiedSince, localFileInfo.ModTime().UTC().Format(http.TimeFormat))
+	if etag, etagErr := readETag(localFilename); etagErr == nil && etag != "" {
+		request.Header.Set(httpIfNoneMatch, etag)
+	}
+}
 
-	if !needsUpdate {
-		return false, nil
+// persistValidators saves the ETag and Last-Modified validators from a successful
+// conditional or resumable download, so that a later call can send accurate
+// If-None-Match/If-Modified-Since headers.
+func persistValidators(localFilename string, header http.Header) {
+	if etag := header.Get(httpETag); etag != "" {
+		writeETag(localFilename, etag)
+	}
+	if lastModifiedHeader := header.Get(httpLastModified); lastModifiedHeader != "" {
+		if lastModified, err := time.Parse(httpTimeFormat, lastModifiedHeader); err == nil {
+			os.Chtimes(localFilename, lastModified, lastModified)
+		}
+	}
+}
+
+func etagFilename(localFilename string) string {
+	return localFilename + ".etag"
+}
+
+func readETag(localFilename string) (string, error) {
+	data, err := os.ReadFile(etagFilename(localFilename))
+	if err != nil {
+		return "", err
 	}
-	return true, Download(remoteURL, localFilename, read)
+	return strings.TrimSpace(string(data)), nil
+}
+
+func writeETag(localFilename, etag string) error {
+	return os.WriteFile(etagFilename(localFilename), []byte(etag), 0644)
+}
+
+// NeedsUpdate checks whether the local copy needs to be updated from the given remote URL,
+// using DefaultClient. If an earlier Download recorded a Cache-Control/Expires freshness
+// window for this file and that window has not yet passed, it returns false without making
+// any HTTP request.
+func NeedsUpdate(remoteURL, localFilename string) (bool, error) {
+	return DefaultClient.NeedsUpdate(context.Background(), remoteURL, localFilename)
+}
+
+// Update updates the local copy from the given remote URL, but only if an update is needed,
+// using DefaultClient.
+func Update(remoteURL, localFilename string, read ReadFunc) (bool, error) {
+	return DefaultClient.Update(context.Background(), remoteURL, localFilename, read)
 }
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -79,6 +80,122 @@ func TestDownload(t *testing.T) {
 	}
 }
 
+func TestDownloadWithOptionsChecksumMismatch(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "localcopy.TestDownloadWithOptions")
+	if err != nil {
+		t.Errorf("failed to create temp dir: %v", err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(tempDir)
+	localFilename := tempDir + "/copy.txt"
+
+	testServer := httptest.NewServer(serveTestdata)
+	defer testServer.Close()
+
+	err = DownloadWithOptions(testServer.URL, localFilename, readTestdata, DownloadOptions{
+		Expected: &Checksum{Algo: SHA256, Hex: "0000000000000000000000000000000000000000000000000000000000000000"},
+	})
+	if err == nil {
+		t.Errorf("expected a checksum mismatch error")
+	}
+	if _, statErr := os.Stat(localFilename); !os.IsNotExist(statErr) {
+		t.Errorf("expected no file to be left behind after a failed checksum verification")
+	}
+	matches, _ := filepath.Glob(tempDir + "/*.tmp")
+	if len(matches) != 0 {
+		t.Errorf("expected no leftover temp files, found %v", matches)
+	}
+}
+
+func TestConditionalDownload(t *testing.T) {
+	tempFile, err := ioutil.TempFile("", "localcopy.TestConditionalDownload")
+	if err != nil {
+		t.Errorf("failed to create temp file: %v", err)
+		t.FailNow()
+	}
+	tempFile.Close()
+	defer os.Remove(tempFile.Name())
+	defer os.Remove(tempFile.Name() + ".etag")
+
+	const etag = `"abc123"`
+	body, err := ioutil.ReadFile(testFilename)
+	if err != nil {
+		t.Errorf("failed to read testdata: %v", err)
+		t.FailNow()
+	}
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write(body)
+	}))
+	defer testServer.Close()
+
+	updated, err := ConditionalDownload(testServer.URL, tempFile.Name(), readTestdata)
+	if err != nil {
+		t.Errorf("failed to download: %v", err)
+		t.FailNow()
+	}
+	if !updated {
+		t.Errorf("expected the first download to report an update")
+	}
+
+	updated, err = ConditionalDownload(testServer.URL, tempFile.Name(), readTestdata)
+	if err != nil {
+		t.Errorf("failed to download: %v", err)
+		t.FailNow()
+	}
+	if updated {
+		t.Errorf("expected the second download to be a no-op due to the ETag validator")
+	}
+}
+
+func TestConditionalDownloadRedownloadsAfterLocalFileDeleted(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "localcopy.TestConditionalDownloadRedownloads")
+	if err != nil {
+		t.Errorf("failed to create temp dir: %v", err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(tempDir)
+	localFilename := tempDir + "/copy.txt"
+
+	const etag = `"stale-etag"`
+	body, err := ioutil.ReadFile(testFilename)
+	if err != nil {
+		t.Errorf("failed to read testdata: %v", err)
+		t.FailNow()
+	}
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write(body)
+	}))
+	defer testServer.Close()
+
+	// Simulate an operator removing a corrupt local copy while its ETag sidecar survives.
+	if err := writeETag(localFilename, etag); err != nil {
+		t.Errorf("failed to seed stale etag sidecar: %v", err)
+		t.FailNow()
+	}
+
+	updated, err := ConditionalDownload(testServer.URL, localFilename, readTestdata)
+	if err != nil {
+		t.Errorf("failed to download: %v", err)
+		t.FailNow()
+	}
+	if !updated {
+		t.Errorf("expected a missing local file to be redownloaded despite a stale ETag sidecar")
+	}
+	if _, err := os.Stat(localFilename); err != nil {
+		t.Errorf("expected the local file to exist after download: %v", err)
+	}
+}
+
 func TestNeedsUpdate(t *testing.T) {
 	tempFile, err := ioutil.TempFile("", "localcopy.TestDownload")
 	if err != nil {
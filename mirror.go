@@ -0,0 +1,247 @@
+package localcopy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// SyncMode selects the download strategy used for a MirrorEntry.
+type SyncMode string
+
+const (
+	// SyncModeConditional (the default) uses ConditionalDownload: a single GET carrying
+	// If-Modified-Since/If-None-Match, with an atomic rename on change.
+	SyncModeConditional SyncMode = "conditional"
+	// SyncModeResume uses ResumeDownload, appending to a ".part" file across calls. Pick
+	// this for large resources that are worth resuming after an interrupted transfer.
+	SyncModeResume SyncMode = "resume"
+)
+
+// MirrorEntry describes a single resource kept in sync by a Mirror.
+type MirrorEntry struct {
+	URL       string
+	LocalPath string
+	SHA256    string   `json:"sha256,omitempty"`
+	Mode      SyncMode `json:"mode,omitempty"`
+}
+
+// MirrorStatus is the outcome of syncing or verifying a single MirrorEntry.
+type MirrorStatus string
+
+const (
+	StatusUpdated  MirrorStatus = "updated"
+	StatusUpToDate MirrorStatus = "up-to-date"
+	StatusFailed   MirrorStatus = "failed"
+)
+
+// MirrorResult is the per-entry outcome of Mirror.Sync or Mirror.Verify.
+type MirrorResult struct {
+	Entry  MirrorEntry
+	Status MirrorStatus
+	Err    error
+}
+
+// Mirror keeps a directory of many mirrored URLs in sync, reusing the conditional-GET,
+// resumable and atomic-rename-with-checksum machinery of this package.
+type Mirror struct {
+	Entries []MirrorEntry
+	// RootDir is the directory Prune scans for files no longer referenced by Entries.
+	RootDir string
+	// Concurrency caps how many entries are synced at once. Values <= 0 default to 4.
+	Concurrency int
+	// Progress, if set, receives a MirrorResult as soon as each entry finishes.
+	Progress chan<- MirrorResult
+	// Client performs the underlying requests; nil uses DefaultClient. Set this to share a
+	// custom *http.Client, retry policy or Logger across every entry. Either way Sync's ctx
+	// is passed to every request, so cancelling it cancels in-flight downloads.
+	Client *Client
+}
+
+// NewMirror returns a Mirror with a default concurrency of 4.
+func NewMirror(entries []MirrorEntry) *Mirror {
+	return &Mirror{Entries: entries, Concurrency: 4}
+}
+
+// LoadManifest parses a JSON-encoded list of MirrorEntry values, as produced by marshalling
+// []MirrorEntry. A YAML manifest can be loaded the same way by decoding into []MirrorEntry
+// with a YAML library before constructing the Mirror.
+func LoadManifest(r io.Reader) ([]MirrorEntry, error) {
+	var entries []MirrorEntry
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %v", err)
+	}
+	return entries, nil
+}
+
+func (m *Mirror) client() *Client {
+	if m.Client != nil {
+		return m.Client
+	}
+	return DefaultClient
+}
+
+// Sync brings every entry up to date, running up to m.Concurrency downloads at once.
+// Results are returned in manifest order regardless of completion order.
+func (m *Mirror) Sync(ctx context.Context) ([]MirrorResult, error) {
+	concurrency := m.Concurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+	client := m.client()
+
+	results := make([]MirrorResult, len(m.Entries))
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, entry := range m.Entries {
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+			wg.Done()
+			continue
+		}
+
+		go func(i int, entry MirrorEntry) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			result := m.syncEntry(ctx, client, entry)
+			results[i] = result
+			m.emit(ctx, result)
+		}(i, entry)
+	}
+
+	wg.Wait()
+	return results, ctx.Err()
+}
+
+func (m *Mirror) syncEntry(ctx context.Context, client *Client, entry MirrorEntry) MirrorResult {
+	options := DownloadOptions{}
+	if entry.SHA256 != "" {
+		options.Expected = &Checksum{Algo: SHA256, Hex: entry.SHA256}
+	}
+
+	var updated bool
+	var err error
+	switch entry.Mode {
+	case SyncModeResume:
+		// ResumeDownloadWithOptions itself sends If-Modified-Since/If-None-Match when no
+		// ".part" file is in progress, so an already-current entry is reported as
+		// up to date without re-downloading anything.
+		updated, err = client.ResumeDownloadWithOptions(ctx, entry.URL, entry.LocalPath, options)
+	default:
+		updated, err = client.ConditionalDownload(ctx, entry.URL, entry.LocalPath, options)
+	}
+	if err != nil {
+		return MirrorResult{Entry: entry, Status: StatusFailed, Err: err}
+	}
+
+	if updated {
+		return MirrorResult{Entry: entry, Status: StatusUpdated}
+	}
+	return MirrorResult{Entry: entry, Status: StatusUpToDate}
+}
+
+// Verify checks every entry's local checksum against the manifest without making any
+// network request. Entries without a SHA256 are reported as up to date.
+func (m *Mirror) Verify(ctx context.Context) ([]MirrorResult, error) {
+	results := make([]MirrorResult, len(m.Entries))
+	for i, entry := range m.Entries {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+
+		result := MirrorResult{Entry: entry, Status: StatusUpToDate}
+		if entry.SHA256 != "" {
+			if err := verifyChecksum(entry.LocalPath, entry.SHA256); err != nil {
+				result.Status = StatusFailed
+				result.Err = err
+			}
+		}
+		results[i] = result
+		m.emit(ctx, result)
+	}
+	return results, nil
+}
+
+// Prune removes local files under RootDir (including their conditional-GET, resume and
+// freshness sidecars) that are no longer referenced by any MirrorEntry, returning the paths
+// removed.
+func (m *Mirror) Prune() ([]string, error) {
+	if m.RootDir == "" {
+		return nil, fmt.Errorf("mirror: RootDir must be set to prune")
+	}
+
+	keep := make(map[string]bool, len(m.Entries))
+	for _, entry := range m.Entries {
+		keep[filepath.Clean(entry.LocalPath)] = true
+	}
+
+	var removed []string
+	err := filepath.Walk(m.RootDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		if keep[filepath.Clean(stripSidecarSuffix(path))] {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		removed = append(removed, path)
+		return nil
+	})
+	return removed, err
+}
+
+var sidecarSuffixes = []string{".etag", ".freshness.json", ".part.meta", ".part"}
+
+func stripSidecarSuffix(path string) string {
+	for _, suffix := range sidecarSuffixes {
+		if strings.HasSuffix(path, suffix) {
+			return strings.TrimSuffix(path, suffix)
+		}
+	}
+	return path
+}
+
+func (m *Mirror) emit(ctx context.Context, result MirrorResult) {
+	if m.Progress == nil {
+		return
+	}
+	select {
+	case m.Progress <- result:
+	case <-ctx.Done():
+	}
+}
+
+func verifyChecksum(localPath, expectedHex string) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s for checksum verification: %v", localPath, err)
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return fmt.Errorf("failed to read %s for checksum verification: %v", localPath, err)
+	}
+
+	if digest := hex.EncodeToString(hasher.Sum(nil)); !strings.EqualFold(digest, expectedHex) {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", localPath, expectedHex, digest)
+	}
+	return nil
+}
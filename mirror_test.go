@@ -0,0 +1,156 @@
+package localcopy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestMirrorSyncAndVerify(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "localcopy.TestMirrorSync")
+	if err != nil {
+		t.Errorf("failed to create temp dir: %v", err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(tempDir)
+
+	body, err := ioutil.ReadFile(testFilename)
+	if err != nil {
+		t.Errorf("failed to read testdata: %v", err)
+		t.FailNow()
+	}
+	sum := sha256.Sum256(body)
+	checksum := hex.EncodeToString(sum[:])
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}))
+	defer testServer.Close()
+
+	mirror := NewMirror([]MirrorEntry{
+		{URL: testServer.URL, LocalPath: tempDir + "/a.txt", SHA256: checksum},
+		{URL: testServer.URL, LocalPath: tempDir + "/b.txt"},
+	})
+	mirror.RootDir = tempDir
+
+	progress := make(chan MirrorResult, len(mirror.Entries))
+	mirror.Progress = progress
+
+	results, err := mirror.Sync(context.Background())
+	if err != nil {
+		t.Errorf("sync failed: %v", err)
+		t.FailNow()
+	}
+
+	events := 0
+	for i := 0; i < len(mirror.Entries); i++ {
+		<-progress
+		events++
+	}
+	if events != len(mirror.Entries) {
+		t.Errorf("expected %d progress events, got %d", len(mirror.Entries), events)
+	}
+
+	for _, result := range results {
+		if result.Status != StatusUpdated {
+			t.Errorf("expected entry %s to be updated, got %s (%v)", result.Entry.LocalPath, result.Status, result.Err)
+		}
+	}
+
+	verifyResults, err := mirror.Verify(context.Background())
+	if err != nil {
+		t.Errorf("verify failed: %v", err)
+		t.FailNow()
+	}
+	for _, result := range verifyResults {
+		if result.Status != StatusUpToDate {
+			t.Errorf("expected entry %s to verify as up to date, got %s (%v)", result.Entry.LocalPath, result.Status, result.Err)
+		}
+	}
+
+	// An untracked file under RootDir should be removed by Prune.
+	strayPath := tempDir + "/stray.txt"
+	if err := os.WriteFile(strayPath, []byte("stray"), 0644); err != nil {
+		t.Errorf("failed to create stray file: %v", err)
+		t.FailNow()
+	}
+
+	mirror.Entries = mirror.Entries[:1]
+	removed, err := mirror.Prune()
+	if err != nil {
+		t.Errorf("prune failed: %v", err)
+		t.FailNow()
+	}
+
+	removedSet := map[string]bool{}
+	for _, path := range removed {
+		removedSet[path] = true
+	}
+	if !removedSet[strayPath] {
+		t.Errorf("expected stray.txt to be pruned, removed: %v", removed)
+	}
+	if !removedSet[tempDir+"/b.txt"] {
+		t.Errorf("expected b.txt to be pruned once no longer in the manifest, removed: %v", removed)
+	}
+	if _, err := os.Stat(tempDir + "/a.txt"); err != nil {
+		t.Errorf("expected a.txt to survive pruning: %v", err)
+	}
+}
+
+func TestMirrorSyncResumeModeUpToDate(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "localcopy.TestMirrorSyncResume")
+	if err != nil {
+		t.Errorf("failed to create temp dir: %v", err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(tempDir)
+
+	body, err := ioutil.ReadFile(testFilename)
+	if err != nil {
+		t.Errorf("failed to read testdata: %v", err)
+		t.FailNow()
+	}
+	const etag = `"resume-mirror-etag"`
+
+	hits := 0
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write(body)
+	}))
+	defer testServer.Close()
+
+	mirror := NewMirror([]MirrorEntry{
+		{URL: testServer.URL, LocalPath: tempDir + "/resumed.txt", Mode: SyncModeResume},
+	})
+
+	results, err := mirror.Sync(context.Background())
+	if err != nil {
+		t.Errorf("sync failed: %v", err)
+		t.FailNow()
+	}
+	if results[0].Status != StatusUpdated {
+		t.Errorf("expected the first sync to report an update, got %s (%v)", results[0].Status, results[0].Err)
+	}
+
+	results, err = mirror.Sync(context.Background())
+	if err != nil {
+		t.Errorf("second sync failed: %v", err)
+		t.FailNow()
+	}
+	if results[0].Status != StatusUpToDate {
+		t.Errorf("expected the second sync of an unchanged resume-mode entry to be up to date, got %s (%v)", results[0].Status, results[0].Err)
+	}
+	if hits != 2 {
+		t.Errorf("expected exactly 2 requests to the server, got %d", hits)
+	}
+}
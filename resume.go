@@ -0,0 +1,239 @@
+package localcopy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// partMeta is the small JSON sidecar ("<file>.part.meta") that tracks the expected total
+// size and a validator (ETag or Last-Modified) for a partial download, so a resumed
+// request can send If-Range and detect when the remote resource changed mid-transfer.
+type partMeta struct {
+	Total     int64  `json:"total,omitempty"`
+	Validator string `json:"validator,omitempty"`
+}
+
+func partFilename(localFilename string) string {
+	return localFilename + ".part"
+}
+
+func partMetaFilename(localFilename string) string {
+	return localFilename + ".part.meta"
+}
+
+func loadPartMeta(localFilename string) (partMeta, error) {
+	var meta partMeta
+	data, err := os.ReadFile(partMetaFilename(localFilename))
+	if err != nil {
+		return meta, err
+	}
+	err = json.Unmarshal(data, &meta)
+	return meta, err
+}
+
+func savePartMeta(localFilename string, meta partMeta) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(partMetaFilename(localFilename), data, 0644)
+}
+
+func removePartFiles(localFilename string) {
+	os.Remove(partFilename(localFilename))
+	os.Remove(partMetaFilename(localFilename))
+}
+
+func validatorFromResponse(response *http.Response) string {
+	if etag := response.Header.Get(httpETag); etag != "" {
+		return etag
+	}
+	return response.Header.Get(httpLastModified)
+}
+
+// parseContentRangeTotal extracts the total resource size from a "Content-Range:
+// bytes start-end/total" header, returning 0 if the total is reported as "*" (unknown).
+func parseContentRangeTotal(contentRange string) (int64, error) {
+	slash := strings.LastIndex(contentRange, "/")
+	if slash < 0 {
+		return 0, fmt.Errorf("malformed Content-Range %q", contentRange)
+	}
+	totalPart := contentRange[slash+1:]
+	if totalPart == "*" {
+		return 0, nil
+	}
+	total, err := strconv.ParseInt(totalPart, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("malformed Content-Range %q: %v", contentRange, err)
+	}
+	return total, nil
+}
+
+// finalizePart verifies and renames a completed ".part" file into place by routing it
+// through downloadAtomic, so resumed downloads get the same checksum verification and
+// atomic-rename guarantees as DownloadWithOptions.
+func finalizePart(partFile, localFilename string, options DownloadOptions) error {
+	file, err := os.Open(partFile)
+	if err != nil {
+		return fmt.Errorf("failed to open partial file: %v", err)
+	}
+	defer file.Close()
+
+	if err := downloadAtomic(file, localFilename, options); err != nil {
+		return err
+	}
+	os.Remove(partFile)
+	os.Remove(partMetaFilename(localFilename))
+	return nil
+}
+
+// ResumeDownload downloads the resource from the given remote URL, resuming a previous
+// partial transfer when "<localFilename>.part" already exists, using DefaultClient. See
+// (*Client).ResumeDownload for details.
+func ResumeDownload(remoteURL, localFilename string) (bool, error) {
+	return DefaultClient.ResumeDownload(context.Background(), remoteURL, localFilename)
+}
+
+// ResumeDownloadWithOptions is ResumeDownload with an optional checksum, using DefaultClient.
+func ResumeDownloadWithOptions(remoteURL, localFilename string, options DownloadOptions) (bool, error) {
+	return DefaultClient.ResumeDownloadWithOptions(context.Background(), remoteURL, localFilename, options)
+}
+
+// ResumeDownload downloads the resource from the given remote URL, resuming a previous
+// partial transfer when "<localFilename>.part" already exists.
+func (c *Client) ResumeDownload(ctx context.Context, remoteURL, localFilename string) (bool, error) {
+	return c.ResumeDownloadWithOptions(ctx, remoteURL, localFilename, DownloadOptions{})
+}
+
+// ResumeDownloadWithOptions downloads the resource from the given remote URL, resuming a
+// previous partial transfer when "<localFilename>.part" already exists, and optionally
+// verifying the result against a checksum.
+//
+// When no partial transfer is in progress it first sends the same If-Modified-Since/
+// If-None-Match validators as ConditionalDownload, so an entry that is already current is
+// reported via (false, nil) without transferring anything. Otherwise it sends a Range
+// request for the missing bytes along with If-Range (from a validator recorded in
+// "<localFilename>.part.meta") so the server can tell it whether the resource is still the
+// one it started downloading. A 206 response appends to the partial file, a 200 response
+// starts over from scratch, and a 416 response means the partial file is already complete.
+// Once the transfer is finished the partial file is verified (if requested) and renamed
+// atomically into localFilename via downloadAtomic.
+func (c *Client) ResumeDownloadWithOptions(ctx context.Context, remoteURL, localFilename string, options DownloadOptions) (bool, error) {
+	partFile := partFilename(localFilename)
+
+	var offset int64
+	var meta partMeta
+	if info, statErr := os.Stat(partFile); statErr == nil {
+		offset = info.Size()
+		meta, _ = loadPartMeta(localFilename)
+	}
+
+	response, err := c.do(ctx, http.MethodGet, remoteURL, nil, func(request *http.Request) {
+		if offset > 0 {
+			request.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+			if meta.Validator != "" {
+				request.Header.Set("If-Range", meta.Validator)
+			}
+			return
+		}
+		// No partial transfer in progress: check whether a previously completed
+		// download is still current before transferring anything.
+		setValidators(request, localFilename)
+	})
+	if err != nil {
+		return false, err
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusNotModified:
+		return false, nil
+
+	case http.StatusRequestedRangeNotSatisfiable:
+		if offset == 0 {
+			removePartFiles(localFilename)
+			return false, fmt.Errorf("failed to download resource: unexpected status %q", response.Status)
+		}
+		if err := finalizePart(partFile, localFilename, options); err != nil {
+			removePartFiles(localFilename)
+			return false, err
+		}
+		persistValidators(localFilename, response.Header)
+		return true, nil
+
+	case http.StatusPartialContent:
+		total, err := parseContentRangeTotal(response.Header.Get("Content-Range"))
+		if err != nil {
+			return false, err
+		}
+		if meta.Total != 0 && total != 0 && total != meta.Total {
+			// The resource changed in a way the server's If-Range check didn't catch;
+			// discard what we have and restart from scratch.
+			removePartFiles(localFilename)
+			return c.ResumeDownloadWithOptions(ctx, remoteURL, localFilename, options)
+		}
+
+		if err := savePartMeta(localFilename, partMeta{Total: total, Validator: validatorFromResponse(response)}); err != nil {
+			return false, fmt.Errorf("failed to persist partial download metadata: %v", err)
+		}
+
+		partFileHandle, err := os.OpenFile(partFile, os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return false, fmt.Errorf("failed to open partial file: %v", err)
+		}
+		if _, err := io.Copy(partFileHandle, response.Body); err != nil {
+			partFileHandle.Close()
+			return false, fmt.Errorf("failed to append to partial file: %v", err)
+		}
+		if err := partFileHandle.Close(); err != nil {
+			return false, fmt.Errorf("failed to close partial file: %v", err)
+		}
+
+		// An open-ended Range request covers everything up to the end of the resource, so
+		// a successful read here (no error from io.Copy) means the partial file is complete.
+		if err := finalizePart(partFile, localFilename, options); err != nil {
+			return false, err
+		}
+		persistValidators(localFilename, response.Header)
+		return true, nil
+
+	case http.StatusOK:
+		partFileHandle, err := os.Create(partFile)
+		if err != nil {
+			return false, fmt.Errorf("failed to create partial file: %v", err)
+		}
+		var total int64
+		if response.ContentLength > 0 {
+			total = response.ContentLength
+		}
+		if err := savePartMeta(localFilename, partMeta{Total: total, Validator: validatorFromResponse(response)}); err != nil {
+			partFileHandle.Close()
+			return false, fmt.Errorf("failed to persist partial download metadata: %v", err)
+		}
+		if _, err := io.Copy(partFileHandle, response.Body); err != nil {
+			partFileHandle.Close()
+			return false, fmt.Errorf("failed to store resource locally: %v", err)
+		}
+		if err := partFileHandle.Close(); err != nil {
+			return false, fmt.Errorf("failed to close partial file: %v", err)
+		}
+
+		if err := finalizePart(partFile, localFilename, options); err != nil {
+			return false, err
+		}
+		persistValidators(localFilename, response.Header)
+		return true, nil
+
+	default:
+		if err := checkStatus(response); err != nil {
+			return false, err
+		}
+		return false, fmt.Errorf("unexpected status %q for resumable download", response.Status)
+	}
+}
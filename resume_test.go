@@ -0,0 +1,120 @@
+package localcopy
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestResumeDownload(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "localcopy.TestResumeDownload")
+	if err != nil {
+		t.Errorf("failed to create temp dir: %v", err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(tempDir)
+	localFilename := tempDir + "/copy.txt"
+
+	body, err := ioutil.ReadFile(testFilename)
+	if err != nil {
+		t.Errorf("failed to read testdata: %v", err)
+		t.FailNow()
+	}
+	const etag = `"resume-etag"`
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		rangeHeader := r.Header.Get("Range")
+		if rangeHeader == "" {
+			w.Write(body)
+			return
+		}
+
+		var start int
+		if _, err := fmt.Sscanf(rangeHeader, "bytes=%d-", &start); err != nil || start >= len(body) {
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		w.Header().Set("Content-Range", "bytes "+strconv.Itoa(start)+"-"+strconv.Itoa(len(body)-1)+"/"+strconv.Itoa(len(body)))
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(body[start:])
+	}))
+	defer testServer.Close()
+
+	// Simulate a previously interrupted download: only the first half was written.
+	half := len(body) / 2
+	if err := os.WriteFile(localFilename+".part", body[:half], 0644); err != nil {
+		t.Errorf("failed to seed partial file: %v", err)
+		t.FailNow()
+	}
+
+	updated, err := ResumeDownload(testServer.URL, localFilename)
+	if err != nil {
+		t.Errorf("failed to resume download: %v", err)
+		t.FailNow()
+	}
+	if !updated {
+		t.Errorf("expected the resumed download to report an update")
+	}
+
+	got, err := ioutil.ReadFile(localFilename)
+	if err != nil {
+		t.Errorf("failed to read downloaded file: %v", err)
+		t.FailNow()
+	}
+	if string(got) != string(body) {
+		t.Errorf("expected resumed download to match full content")
+	}
+	if _, err := os.Stat(localFilename + ".part"); !os.IsNotExist(err) {
+		t.Errorf("expected the partial file to be gone after completion")
+	}
+}
+
+func TestResumeDownloadRedownloadsAfterLocalFileDeleted(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "localcopy.TestResumeDownloadRedownloads")
+	if err != nil {
+		t.Errorf("failed to create temp dir: %v", err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(tempDir)
+	localFilename := tempDir + "/copy.txt"
+
+	const etag = `"stale-resume-etag"`
+	body, err := ioutil.ReadFile(testFilename)
+	if err != nil {
+		t.Errorf("failed to read testdata: %v", err)
+		t.FailNow()
+	}
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write(body)
+	}))
+	defer testServer.Close()
+
+	// Simulate an operator removing a corrupt local copy while its ETag sidecar survives,
+	// and no ".part" file is in progress.
+	if err := writeETag(localFilename, etag); err != nil {
+		t.Errorf("failed to seed stale etag sidecar: %v", err)
+		t.FailNow()
+	}
+
+	updated, err := ResumeDownload(testServer.URL, localFilename)
+	if err != nil {
+		t.Errorf("failed to resume download: %v", err)
+		t.FailNow()
+	}
+	if !updated {
+		t.Errorf("expected a missing local file to be redownloaded despite a stale ETag sidecar")
+	}
+	if _, err := os.Stat(localFilename); err != nil {
+		t.Errorf("expected the local file to exist after download: %v", err)
+	}
+}